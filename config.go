@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Duration wraps time.Duration so target/module config files can use
+// human-friendly strings like "5s" or "1m30s" in either YAML or JSON.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %s", s, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %s", s, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Target describes a single host to be continuously monitored by its own
+// pinger goroutine.
+type Target struct {
+	Name       string   `yaml:"name" json:"name"` // used as the "target" metric label instead of Host, if set
+	Host       string   `yaml:"host" json:"host"`
+	Mode       string   `yaml:"mode" json:"mode"` // "icmp" (default), "http", or "https"
+	Interval   Duration `yaml:"interval" json:"interval"`
+	Timeout    Duration `yaml:"timeout" json:"timeout"`
+	Count      int      `yaml:"count" json:"count"`
+	PacketSize int      `yaml:"packet_size" json:"packet_size"`
+	Source     string   `yaml:"source" json:"source"`
+	Network    string   `yaml:"network" json:"network"`      // "ip4" or "ip6", empty means either
+	Privileged bool     `yaml:"privileged" json:"privileged"` // use raw ICMP sockets instead of unprivileged UDP datagram sockets
+	TOS        int      `yaml:"tos" json:"tos"`               // DSCP/ToS marking for outgoing packets, best-effort
+
+	// HTTP(S) mode only
+	HTTPMethod       string            `yaml:"http_method" json:"http_method"`
+	HTTPHeader       map[string]string `yaml:"http_header" json:"http_header"`
+	HTTPExpectStatus int               `yaml:"http_expect_status" json:"http_expect_status"`
+	HTTPForceHTTP11  bool              `yaml:"http1.1" json:"http1.1"`
+	HTTPKeepAlive    bool              `yaml:"keepalive" json:"keepalive"`
+}
+
+// targetLabel returns the value used for the "target" metric label: Name if
+// set (LoadConfig defaults it to Host when omitted), otherwise Host
+// directly, which covers targets built outside LoadConfig (the legacy
+// single-host CLI invocation and the on-demand /probe handler).
+func targetLabel(t Target) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Host
+}
+
+// Module describes the default probe parameters used for an on-demand
+// /probe?target=...&module=... scrape, mirroring blackbox_exporter's module
+// concept.
+type Module struct {
+	Mode       string   `yaml:"mode" json:"mode"`
+	Interval   Duration `yaml:"interval" json:"interval"`
+	Timeout    Duration `yaml:"timeout" json:"timeout"`
+	Count      int      `yaml:"count" json:"count"`
+	PacketSize int      `yaml:"packet_size" json:"packet_size"`
+	Source     string   `yaml:"source" json:"source"`
+	Network    string   `yaml:"network" json:"network"`
+	Privileged bool     `yaml:"privileged" json:"privileged"`
+	TOS        int      `yaml:"tos" json:"tos"`
+
+	HTTPMethod       string            `yaml:"http_method" json:"http_method"`
+	HTTPHeader       map[string]string `yaml:"http_header" json:"http_header"`
+	HTTPExpectStatus int               `yaml:"http_expect_status" json:"http_expect_status"`
+	HTTPForceHTTP11  bool              `yaml:"http1.1" json:"http1.1"`
+	HTTPKeepAlive    bool              `yaml:"keepalive" json:"keepalive"`
+}
+
+// Config is the top-level shape of the -targets-file.
+type Config struct {
+	Targets []Target          `yaml:"targets" json:"targets"`
+	Modules map[string]Module `yaml:"modules" json:"modules"`
+}
+
+// LoadConfig reads and parses a targets file. JSON is used for paths ending
+// in ".json"; everything else is parsed as YAML (which is a superset of
+// JSON anyway, but the explicit split keeps error messages readable).
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read targets file: %s", err)
+	}
+
+	cfg := &Config{}
+	if strings.HasSuffix(path, ".json") {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("unable to parse targets file as JSON: %s", err)
+		}
+	} else {
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("unable to parse targets file as YAML: %s", err)
+		}
+	}
+
+	for i := range cfg.Targets {
+		if cfg.Targets[i].Host == "" {
+			return nil, fmt.Errorf("target %d is missing a host", i)
+		}
+		if cfg.Targets[i].Name == "" {
+			cfg.Targets[i].Name = cfg.Targets[i].Host
+		}
+		if cfg.Targets[i].Interval == 0 {
+			cfg.Targets[i].Interval = Duration(time.Second)
+		}
+		if cfg.Targets[i].Timeout == 0 {
+			cfg.Targets[i].Timeout = Duration(5 * time.Second)
+		}
+		if cfg.Targets[i].Count == 0 {
+			cfg.Targets[i].Count = -1
+		}
+	}
+
+	return cfg, nil
+}
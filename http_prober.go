@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+)
+
+// HTTPProber probes a target by issuing a single HTTP(S) request and timing
+// its phases (DNS, connect, TLS, first byte, total) via httptrace. Its
+// http.Client is built once, by NewHTTPProber, and reused across repeated
+// Probe calls so Target.HTTPKeepAlive can actually keep a connection alive
+// between probes.
+type HTTPProber struct {
+	Target Target
+	client *http.Client
+}
+
+// NewHTTPProber builds an HTTPProber for target, constructing its
+// http.Client once so it can be reused for the lifetime of the prober.
+func NewHTTPProber(target Target) *HTTPProber {
+	transport := &http.Transport{
+		DisableKeepAlives: !target.HTTPKeepAlive,
+	}
+	if target.HTTPForceHTTP11 {
+		// Prevent the transport from negotiating HTTP/2 over TLS.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &HTTPProber{
+		Target: target,
+		client: &http.Client{Transport: transport},
+	}
+}
+
+func (p *HTTPProber) Probe(ctx context.Context) (*Result, error) {
+	method := p.Target.HTTPMethod
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	url := p.Target.Host
+	if !strings.Contains(url, "://") {
+		scheme := "http"
+		if p.Target.Mode == "https" {
+			scheme = "https"
+		}
+		url = scheme + "://" + url
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(p.Target.Timeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("target %s: %s", p.Target.Host, err)
+	}
+	for k, v := range p.Target.HTTPHeader {
+		req.Header.Set(k, v)
+	}
+
+	phases := map[string]time.Duration{}
+	var dnsStart, connectStart, tlsStart, start time.Time
+
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { phases["dns"] = time.Since(dnsStart) },
+		ConnectStart:         func(string, string) { connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { phases["connect"] = time.Since(connectStart) },
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { phases["tls"] = time.Since(tlsStart) },
+		GotFirstResponseByte: func() { phases["first_byte"] = time.Since(start) },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	result := &Result{Phases: phases}
+
+	start = time.Now()
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("target %s: %s", p.Target.Host, err)
+	}
+	defer resp.Body.Close()
+	_, _ = ioutil.ReadAll(resp.Body) // drain so total reflects the full response
+
+	result.Duration = time.Since(start)
+	phases["total"] = result.Duration
+	result.StatusCode = resp.StatusCode
+
+	expect := p.Target.HTTPExpectStatus
+	if expect == 0 {
+		expect = http.StatusOK
+	}
+	result.Success = resp.StatusCode == expect
+
+	return result, nil
+}
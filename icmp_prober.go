@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-ping/ping"
+)
+
+// ICMPProber probes a target by sending Target.Count ICMP echo requests and
+// waiting for their replies (or Target.Timeout). By default it uses
+// unprivileged UDP datagram sockets (go-ping/ping's "udp4"/"udp6" network),
+// which don't require CAP_NET_RAW; set Target.Privileged to fall back to raw
+// ICMP sockets.
+//
+// Metrics/Labels are optional. When set (continuous -targets-file/legacy
+// mode), per-packet metrics are updated live from OnRecv/OnDuplicateRecv
+// instead of waiting for Probe to return, since Target.Count defaults to
+// -1 (infinite) and Probe may then never return until shutdown.
+type ICMPProber struct {
+	Target  Target
+	Metrics *Metrics
+	Labels  []string
+}
+
+func (p *ICMPProber) Probe(ctx context.Context) (*Result, error) {
+	pinger, err := ping.NewPinger(p.Target.Host)
+	if err != nil {
+		return nil, fmt.Errorf("target %s: %s", p.Target.Host, err)
+	}
+
+	result := &Result{}
+
+	pinger.OnSend = func(pkt *ping.Packet) {
+		if p.Metrics != nil {
+			p.Metrics.PacketsSent.WithLabelValues(p.Labels...).Inc()
+		}
+	}
+
+	pinger.OnRecv = func(pkt *ping.Packet) {
+		result.RTTSamples = append(result.RTTSamples, pkt.Rtt)
+		result.ReplyTTL = pkt.Ttl
+		logRecv("msg", "received ICMP reply", "target", p.Target.Host,
+			"bytes", pkt.Nbytes, "addr", pkt.IPAddr, "icmp_seq", pkt.Seq, "rtt", pkt.Rtt)
+
+		if p.Metrics != nil {
+			rttSeconds := pkt.Rtt.Seconds()
+			p.Metrics.RTT.WithLabelValues(p.Labels...).Set(float64(pkt.Rtt) / float64(time.Millisecond))
+			p.Metrics.RTTHistogram.WithLabelValues(p.Labels...).Observe(rttSeconds)
+			p.Metrics.RTTSummary.WithLabelValues(p.Labels...).Observe(rttSeconds)
+			p.Metrics.PacketsRecv.WithLabelValues(p.Labels...).Inc()
+			p.Metrics.ReplyTTL.WithLabelValues(p.Labels...).Set(float64(pkt.Ttl))
+		}
+	}
+
+	pinger.OnDuplicateRecv = func(pkt *ping.Packet) {
+		result.PacketsDup++
+		logRecv("msg", "received duplicate ICMP reply", "target", p.Target.Host,
+			"addr", pkt.IPAddr, "icmp_seq", pkt.Seq)
+
+		if p.Metrics != nil {
+			p.Metrics.PacketsDuplicate.WithLabelValues(p.Labels...).Inc()
+		}
+	}
+
+	pinger.Count = p.Target.Count
+	pinger.Interval = time.Duration(p.Target.Interval)
+	pinger.Timeout = time.Duration(p.Target.Timeout)
+	pinger.SetPrivileged(p.Target.Privileged)
+
+	if p.Target.Source != "" {
+		pinger.Source = p.Target.Source
+	}
+	if p.Target.PacketSize > 0 {
+		pinger.Size = p.Target.PacketSize
+	}
+	switch p.Target.Network {
+	case "ip4":
+		pinger.SetNetwork("ip4")
+	case "ip6":
+		pinger.SetNetwork("ip6")
+	}
+	if p.Target.TOS != 0 {
+		// go-ping/ping doesn't expose a socket option for DSCP/ToS marking;
+		// the field is plumbed through targets.yml for forward-compatibility
+		// but has no effect yet.
+		level.Warn(logger).Log("msg", "tos is configured but not supported by the ICMP prober", "target", p.Target.Host, "tos", p.Target.TOS)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			pinger.Stop()
+		case <-stop:
+		}
+	}()
+
+	level.Debug(logger).Log("msg", "starting probe", "target", pinger.Addr(), "addr", pinger.IPAddr(), "privileged", p.Target.Privileged)
+
+	start := time.Now()
+	runErr := pinger.Run() // Blocking until Count packets replied/timed out, Stop(), or a socket error
+	result.Duration = time.Since(start)
+	if runErr != nil {
+		return nil, fmt.Errorf("target %s: %s", p.Target.Host, runErr)
+	}
+
+	stats := pinger.Statistics()
+	level.Info(logger).Log("msg", "probe finished", "target", stats.Addr,
+		"packets_sent", stats.PacketsSent, "packets_recv", stats.PacketsRecv, "packet_loss_pct", stats.PacketLoss,
+		"rtt_min", stats.MinRtt, "rtt_avg", stats.AvgRtt, "rtt_max", stats.MaxRtt, "rtt_stddev", stats.StdDevRtt)
+
+	result.PacketsSent = stats.PacketsSent
+	result.PacketsRecv = stats.PacketsRecv
+	result.RTTMin = stats.MinRtt
+	result.RTTAvg = stats.AvgRtt
+	result.RTTMax = stats.MaxRtt
+	result.RTTStdDev = stats.StdDevRtt
+	result.Success = stats.PacketsRecv > 0
+
+	if p.Metrics != nil {
+		lossRatio := 0.0
+		if stats.PacketsSent > 0 {
+			lossRatio = float64(stats.PacketsSent-stats.PacketsRecv) / float64(stats.PacketsSent)
+		}
+		p.Metrics.PacketLossRatio.WithLabelValues(p.Labels...).Set(lossRatio)
+		p.Metrics.RTTMin.WithLabelValues(p.Labels...).Set(stats.MinRtt.Seconds())
+		p.Metrics.RTTAvg.WithLabelValues(p.Labels...).Set(stats.AvgRtt.Seconds())
+		p.Metrics.RTTMax.WithLabelValues(p.Labels...).Set(stats.MaxRtt.Seconds())
+		p.Metrics.RTTStdDev.WithLabelValues(p.Labels...).Set(stats.StdDevRtt.Seconds())
+	}
+
+	return result, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/common/promlog"
+)
+
+// logger is the process-wide leveled logger, configured by initLogger from
+// -log.level/-log.format. It defaults to a no-op so packages that run
+// before main() finishes flag parsing never nil-dereference it.
+var logger = log.NewNopLogger()
+
+// quiet suppresses the per-packet/per-request log lines emitted by probers,
+// while still logging probe summaries and errors. Set from -q/--quiet.
+var quiet bool
+
+// initLogger configures the package-level logger from -log.level and
+// -log.format.
+func initLogger(levelStr, formatStr string) error {
+	var allowedLevel promlog.AllowedLevel
+	if err := allowedLevel.Set(levelStr); err != nil {
+		return err
+	}
+	var allowedFormat promlog.AllowedFormat
+	if err := allowedFormat.Set(formatStr); err != nil {
+		return err
+	}
+
+	logger = promlog.New(&promlog.Config{Level: &allowedLevel, Format: &allowedFormat})
+	return nil
+}
+
+// logRecv logs a single received packet/response line, unless -q/--quiet
+// was given.
+func logRecv(keyvals ...interface{}) {
+	if quiet {
+		return
+	}
+	level.Info(logger).Log(keyvals...)
+}
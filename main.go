@@ -25,29 +25,78 @@ package main
 
 /*
 Based upon:
-- github.com/sparrc/go-ping
+- github.com/go-ping/ping (maintained fork of github.com/sparrc/go-ping)
 - github.com/paihu/netflow_exporter
 */
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-
-	"github.com/sparrc/go-ping"
+	"golang.org/x/sync/errgroup"
 )
 
+// headerFlag collects repeated -http-header "Key: Value" flags into a map.
+type headerFlag struct {
+	m map[string]string
+}
+
+func (h *headerFlag) String() string {
+	return fmt.Sprintf("%v", h.m)
+}
+
+func (h *headerFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("expected \"Key: Value\", got %q", value)
+	}
+	if h.m == nil {
+		h.m = map[string]string{}
+	}
+	h.m[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	return nil
+}
+
+// parseRTTBuckets parses a comma-separated list of bucket boundaries (in
+// seconds). An empty string means "use the default buckets".
+func parseRTTBuckets(s string) ([]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	buckets := make([]float64, 0, len(parts))
+	for _, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -rtt-buckets value %q: %s", p, err)
+		}
+		buckets = append(buckets, v)
+	}
+	return buckets, nil
+}
+
 var usage = `
 Usage:
 
     ping [-bind-addr listen-address] [-c count] [-i interval] [-t timeout] host
+    ping -targets-file targets.yml [-bind-addr listen-address]
+
+A single host may be given on the command line for one-shot/legacy use, or
+a -targets-file may be given to monitor many hosts at once, each with its
+own ping interval/timeout/count. Either way, /probe?target=host is always
+available for on-demand, blackbox_exporter-style scrapes.
 `
 
 func main() {
@@ -55,83 +104,140 @@ func main() {
 	pInterval := flag.Duration("i", time.Second, "Interval between ICMP requests.")
 	pCount := flag.Int("c", -1, "Number of ICMP requests to send, defaults to infinity.")
 	pMetricsPath := flag.String("metrics-path", "/metrics", "Path under which to expose Prometheus metrics.")
+	pProbePath := flag.String("probe-path", "/probe", "Path under which to expose on-demand probe results.")
 	pListenAddress := flag.String("bind-addr", ":9999", "Address on which to expose metrics.")
+	pTargetsFile := flag.String("targets-file", "", "Path to a YAML/JSON file listing targets to monitor continuously.")
+	pRTTBuckets := flag.String("rtt-buckets", "", "Comma-separated list of ping_rtt_seconds histogram buckets, in seconds. Defaults to a built-in range.")
+	pMode := flag.String("mode", "icmp", "Probe mode for the single-host/legacy invocation: icmp, http, or https.")
+	pPrivileged := flag.Bool("privileged", false, "Use raw ICMP sockets instead of unprivileged UDP datagram sockets. Requires CAP_NET_RAW (or root) when true.")
+	pSource := flag.String("source", "", "Source address to ping from.")
+	pSize := flag.Int("size", 24, "ICMP payload size in bytes.")
+	pTOS := flag.Int("tos", 0, "DSCP/ToS marking for outgoing ICMP packets (best-effort).")
+	pNetwork := flag.String("network", "", "Force ICMP resolution to \"ip4\" or \"ip6\"; empty means either.")
+	pHTTPMethod := flag.String("http-method", "GET", "HTTP method to use in http/https mode.")
+	pHTTPExpectStatus := flag.Int("http-expect-status", 200, "HTTP status code that counts as success in http/https mode.")
+	pHTTP11 := flag.Bool("http1.1", false, "Force HTTP/1.1, disabling HTTP/2 negotiation, in http/https mode.")
+	pKeepAlive := flag.Bool("keepalive", true, "Reuse connections between probes in http/https mode.")
+	var pHTTPHeaders headerFlag
+	flag.Var(&pHTTPHeaders, "http-header", "HTTP header to send in http/https mode, as \"Key: Value\". May be repeated.")
+	pLogLevel := flag.String("log.level", "info", "Only log messages with the given severity or above. One of: [debug, info, warn, error]")
+	pLogFormat := flag.String("log.format", "logfmt", "Output format of log messages. One of: [logfmt, json]")
+	flag.BoolVar(&quiet, "q", false, "Suppress per-packet/per-request log lines; summary and error events are still logged.")
+	flag.BoolVar(&quiet, "quiet", false, "Alias for -q.")
 
 	flag.Parse()
-	if flag.NArg() == 0 {
-		fmt.Println(usage)
-		return
+
+	if err := initLogger(*pLogLevel, *pLogFormat); err != nil {
+		fmt.Printf("ERROR: %s\n", err.Error())
+		os.Exit(1)
 	}
 
-	hostname, err := os.Hostname()
+	rttBuckets, err := parseRTTBuckets(*pRTTBuckets)
 	if err != nil {
-		fmt.Println("Unable to get hostname")
-		fmt.Println(err)
+		level.Error(logger).Log("msg", "invalid -rtt-buckets", "err", err)
+		os.Exit(1)
+	}
+
+	var targets []Target
+	var cfg *Config
+	if *pTargetsFile != "" {
+		var err error
+		cfg, err = LoadConfig(*pTargetsFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "unable to load targets file", "err", err)
+			os.Exit(1)
+		}
+		targets = cfg.Targets
+	} else if flag.NArg() > 0 {
+		targets = []Target{{
+			Host:             flag.Arg(0),
+			Mode:             *pMode,
+			Interval:         Duration(*pInterval),
+			Timeout:          Duration(*pTimeout),
+			Count:            *pCount,
+			PacketSize:       *pSize,
+			Source:           *pSource,
+			Network:          *pNetwork,
+			Privileged:       *pPrivileged,
+			TOS:              *pTOS,
+			HTTPMethod:       *pHTTPMethod,
+			HTTPHeader:       pHTTPHeaders.m,
+			HTTPExpectStatus: *pHTTPExpectStatus,
+			HTTPForceHTTP11:  *pHTTP11,
+			HTTPKeepAlive:    *pKeepAlive,
+		}}
+	} else {
+		fmt.Println(usage)
 		return
 	}
 
-	// Set up Prometheus GaugeVec object
-	pingGaugeVec := promauto.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "ping_rtt",
-			Help: "Historical ping RTTs over time (ms)",
-		},
-		[]string{
-			"targetHost", // Specify ping target
-			"hostname",   // Name of host running ping-exporter
-		},
-	)
-
-	// Map Prometheus metrics scrape path to handler function
-	http.Handle(*pMetricsPath, promhttp.Handler())
-
-	// Parse target host and create Pinger object
-	targetHost := flag.Arg(0)
-	pinger, err := ping.NewPinger(targetHost)
+	hostname, err := os.Hostname()
 	if err != nil {
-		fmt.Printf("ERROR: %s\n", err.Error())
+		level.Error(logger).Log("msg", "unable to get hostname", "err", err)
 		return
 	}
 
-	// Listen for interrupt signal (SIGINT), i.e. Ctrl+C and stop pinger
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	metrics := NewMetrics(prometheus.DefaultRegisterer, rttBuckets)
+
+	mux := http.NewServeMux()
+	mux.Handle(*pMetricsPath, promhttp.Handler())
+	mux.HandleFunc(*pProbePath, newProbeHandler(cfg))
+	srv := &http.Server{Addr: *pListenAddress, Handler: mux}
+
+	// ctx is cancelled on SIGINT/SIGTERM and tells every pinger goroutine to
+	// stop; the http.Server is shut down separately below, once pingers have
+	// had a chance to drain.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	serverErrCh := make(chan error, 1)
 	go func() {
-		for _ = range c {
-			pinger.Stop()
+		defer close(serverErrCh)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serverErrCh <- err
 		}
 	}()
-
-	// Get Gauge object with targetHost
-	pingGauge := pingGaugeVec.WithLabelValues(targetHost, hostname)
-
-	// Define OnRecv function for receiving ICMPs => Update gauge
-	pinger.OnRecv = func(pkt *ping.Packet) {
-		pingGauge.Set(float64(pkt.Rtt) / 1000000) // Convert to ns to ms
-		fmt.Printf("%d bytes from %s: icmp_seq=%d time=%v\n",
-			pkt.Nbytes, pkt.IPAddr, pkt.Seq, pkt.Rtt)
+	level.Info(logger).Log("msg", "listening", "addr", *pListenAddress)
+
+	// Run one pinger goroutine per target under an errgroup, so a single
+	// target failing doesn't prevent us from waiting for the rest to drain.
+	var g errgroup.Group
+	for _, target := range targets {
+		target := target
+		prober, err := NewProber(target, metrics, hostname)
+		if err != nil {
+			level.Error(logger).Log("msg", "unable to build prober", "target", target.Host, "err", err)
+			continue
+		}
+		g.Go(func() error {
+			return runScheduled(ctx, target, prober, metrics, hostname)
+		})
 	}
 
-	// Stats function when ping ends
-	pinger.OnFinish = func(stats *ping.Statistics) {
-		fmt.Printf("\n--- %s ping statistics ---\n", stats.Addr)
-		fmt.Printf("%d packets transmitted, %d packets received, %v%% packet loss\n",
-			stats.PacketsSent, stats.PacketsRecv, stats.PacketLoss)
-		fmt.Printf("round-trip min/avg/max/stddev = %v/%v/%v/%v\n",
-			stats.MinRtt, stats.AvgRtt, stats.MaxRtt, stats.StdDevRtt)
+	select {
+	case <-sigCh:
+		level.Info(logger).Log("msg", "received shutdown signal")
+	case err := <-serverErrCh:
+		level.Error(logger).Log("msg", "http server failed to bind", "err", err)
+		cancel()
+		g.Wait()
+		os.Exit(1)
 	}
 
-	pinger.Count = *pCount
-	pinger.Interval = *pInterval
-	pinger.Timeout = *pTimeout
-	pinger.SetPrivileged(true)
-
-	// Start server in separate goroutine
-	go http.ListenAndServe(*pListenAddress, nil)
-	fmt.Printf("Now listening on %s\n", *pListenAddress)
+	// Stop the pingers first, then give in-flight HTTP requests (including
+	// /probe scrapes, which block for the duration of a probe) time to
+	// finish before the process exits.
+	cancel()
 
-	fmt.Printf("PING %s (%s):\n", pinger.Addr(), pinger.IPAddr())
-	pinger.Run() // Blocking
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		level.Error(logger).Log("msg", "error shutting down http server", "err", err)
+	}
 
-	return
+	if err := g.Wait(); err != nil {
+		level.Error(logger).Log("msg", "pinger error", "err", err)
+	}
 }
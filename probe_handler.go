@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// defaultModule is used when the request's module query parameter is empty
+// or unknown, or when the exporter was started without a -targets-file.
+var defaultModule = Module{
+	Mode:     "icmp",
+	Interval: Duration(time.Second),
+	Timeout:  Duration(5 * time.Second),
+	Count:    3,
+}
+
+// newProbeHandler returns a blackbox_exporter-style handler for
+// /probe?target=host&module=name. Each request builds a Prober from the
+// requested module, runs a single probe, and serves a per-scrape registry
+// populated only with that probe's results.
+func newProbeHandler(cfg *Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		targetHost := r.URL.Query().Get("target")
+		if targetHost == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+
+		module := defaultModule
+		if cfg != nil {
+			if m, ok := cfg.Modules[r.URL.Query().Get("module")]; ok {
+				module = m
+			}
+		}
+
+		target := Target{
+			Host:             targetHost,
+			Mode:             module.Mode,
+			Interval:         module.Interval,
+			Timeout:          module.Timeout,
+			Count:            module.Count,
+			PacketSize:       module.PacketSize,
+			Source:           module.Source,
+			Network:          module.Network,
+			Privileged:       module.Privileged,
+			TOS:              module.TOS,
+			HTTPMethod:       module.HTTPMethod,
+			HTTPHeader:       module.HTTPHeader,
+			HTTPExpectStatus: module.HTTPExpectStatus,
+			HTTPForceHTTP11:  module.HTTPForceHTTP11,
+			HTTPKeepAlive:    module.HTTPKeepAlive,
+		}
+		if target.Count <= 0 {
+			target.Count = defaultModule.Count
+		}
+		if target.Timeout <= 0 {
+			target.Timeout = defaultModule.Timeout
+		}
+		if target.Interval <= 0 {
+			target.Interval = defaultModule.Interval
+		}
+
+		prober, err := NewProber(target, nil, "")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		reg := prometheus.NewRegistry()
+		probeSuccessGauge := promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "probe_success",
+			Help: "Whether the probe succeeded",
+		})
+		probeDurationGauge := promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "probe_duration_seconds",
+			Help: "Total time taken for the probe to complete",
+		})
+
+		result, err := prober.Probe(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		probeDurationGauge.Set(result.Duration.Seconds())
+		if result.Success {
+			probeSuccessGauge.Set(1)
+		} else {
+			probeSuccessGauge.Set(0)
+		}
+
+		switch target.Mode {
+		case "http", "https":
+			httpStatusGauge := promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+				Name: "probe_http_status_code",
+				Help: "HTTP status code returned by the probe",
+			})
+			httpStatusGauge.Set(float64(result.StatusCode))
+
+			httpDurationGaugeVec := promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+				Name: "probe_http_duration_seconds",
+				Help: "Time taken by each phase of the HTTP(S) probe",
+			}, []string{"phase"})
+			for phase, d := range result.Phases {
+				httpDurationGaugeVec.WithLabelValues(phase).Set(d.Seconds())
+			}
+		default:
+			probeTTLGauge := promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+				Name: "probe_icmp_reply_ttl",
+				Help: "TTL reported on the last ICMP reply",
+			})
+			probeTTLGauge.Set(float64(result.ReplyTTL))
+
+			probeRTTGaugeVec := promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+				Name: "probe_icmp_rtt_seconds",
+				Help: "Round-trip time for the probe, by aggregation",
+			}, []string{"stat"}) // min, avg, max, stddev
+			probeRTTGaugeVec.WithLabelValues("min").Set(result.RTTMin.Seconds())
+			probeRTTGaugeVec.WithLabelValues("avg").Set(result.RTTAvg.Seconds())
+			probeRTTGaugeVec.WithLabelValues("max").Set(result.RTTMax.Seconds())
+			probeRTTGaugeVec.WithLabelValues("stddev").Set(result.RTTStdDev.Seconds())
+		}
+
+		promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
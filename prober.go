@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Result is the outcome of a single probe, whether it came from an ICMP
+// pinger or an HTTP(S) request. Fields that don't apply to a given Prober
+// are left zero-valued.
+type Result struct {
+	Success  bool
+	Duration time.Duration
+
+	// ICMP fields
+	PacketsSent                       int
+	PacketsRecv                       int
+	PacketsDup                        int
+	RTTSamples                        []time.Duration
+	RTTMin, RTTAvg, RTTMax, RTTStdDev time.Duration
+	ReplyTTL                          int
+
+	// HTTP(S) fields
+	StatusCode int
+	Phases     map[string]time.Duration // dns, connect, tls, first_byte, total
+}
+
+// Prober runs a single scrape against a target and returns its Result. It
+// is implemented by ICMPProber and HTTPProber so that both modes can share
+// the same scheduling and registration code in scheduler.go and
+// probe_handler.go.
+type Prober interface {
+	Probe(ctx context.Context) (*Result, error)
+}
+
+// NewProber builds the Prober appropriate for target.Mode. metrics/hostname
+// are used only by ICMPProber, to update metrics live from OnRecv since
+// target.Count defaults to -1 (infinite) and Probe may then never return
+// until shutdown; pass a nil metrics (e.g. from the on-demand /probe
+// handler) to get a Prober that doesn't touch any shared registry.
+func NewProber(target Target, metrics *Metrics, hostname string) (Prober, error) {
+	switch target.Mode {
+	case "", "icmp":
+		p := &ICMPProber{Target: target, Metrics: metrics}
+		if metrics != nil {
+			p.Labels = []string{targetLabel(target), hostname}
+		}
+		return p, nil
+	case "http", "https":
+		return NewHTTPProber(target), nil
+	default:
+		return nil, fmt.Errorf("unknown mode %q", target.Mode)
+	}
+}
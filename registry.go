@@ -0,0 +1,193 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultRTTBuckets is used for ping_rtt_seconds unless -rtt-buckets
+// overrides it. It spans typical LAN (sub-ms) to badly-congested WAN
+// (multi-second) round trips.
+var defaultRTTBuckets = []float64{
+	.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10,
+}
+
+// Metrics bundles the Prometheus collectors updated by a pinger. A fresh
+// Metrics is created per-scrape for the /probe handler, and once globally
+// for the continuously-running -targets-file pingers.
+type Metrics struct {
+	RTT          *prometheus.GaugeVec // Deprecated: superseded by RTTSeconds/RTTHistogram, kept for existing dashboards
+	RTTHistogram *prometheus.HistogramVec
+	RTTSummary   *prometheus.SummaryVec
+
+	PacketsSent      *prometheus.CounterVec
+	PacketsRecv      *prometheus.CounterVec
+	PacketsDuplicate *prometheus.CounterVec
+
+	PacketLossRatio *prometheus.GaugeVec
+	RTTMin          *prometheus.GaugeVec
+	RTTAvg          *prometheus.GaugeVec
+	RTTMax          *prometheus.GaugeVec
+	RTTStdDev       *prometheus.GaugeVec
+	ReplyTTL        *prometheus.GaugeVec
+
+	HTTPDuration *prometheus.GaugeVec // by "phase": dns, connect, tls, first_byte, total
+	HTTPStatus   *prometheus.GaugeVec
+	HTTPSuccess  *prometheus.GaugeVec
+}
+
+// NewMetrics registers the ping collectors against reg and returns the
+// bundle used to update them. rttBuckets configures the ping_rtt_seconds
+// histogram; pass nil to use defaultRTTBuckets.
+func NewMetrics(reg prometheus.Registerer, rttBuckets []float64) *Metrics {
+	if rttBuckets == nil {
+		rttBuckets = defaultRTTBuckets
+	}
+
+	labels := []string{
+		"target",   // Ping target host
+		"hostname", // Name of host running ping-exporter
+	}
+
+	m := &Metrics{
+		RTT: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ping_rtt",
+				Help: "Historical ping RTTs over time (ms)",
+			},
+			labels,
+		),
+		RTTHistogram: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "ping_rtt_seconds",
+				Help:    "Histogram of ping round-trip times",
+				Buckets: rttBuckets,
+			},
+			labels,
+		),
+		RTTSummary: prometheus.NewSummaryVec(
+			prometheus.SummaryOpts{
+				Name:       "ping_rtt_seconds_summary",
+				Help:       "Summary of ping round-trip times",
+				Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+			},
+			labels,
+		),
+		PacketsSent: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ping_packets_sent_total",
+				Help: "Total number of ICMP echo requests sent",
+			},
+			labels,
+		),
+		PacketsRecv: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ping_packets_received_total",
+				Help: "Total number of ICMP echo replies received",
+			},
+			labels,
+		),
+		PacketsDuplicate: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ping_packets_duplicate_total",
+				Help: "Total number of duplicate ICMP echo replies received",
+			},
+			labels,
+		),
+		PacketLossRatio: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ping_packet_loss_ratio",
+				Help: "Most recent packet loss ratio, from 0 to 1",
+			},
+			labels,
+		),
+		RTTMin: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ping_rtt_min_seconds",
+				Help: "Minimum round-trip time over the most recent ping run",
+			},
+			labels,
+		),
+		RTTAvg: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ping_rtt_avg_seconds",
+				Help: "Average round-trip time over the most recent ping run",
+			},
+			labels,
+		),
+		RTTMax: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ping_rtt_max_seconds",
+				Help: "Maximum round-trip time over the most recent ping run",
+			},
+			labels,
+		),
+		RTTStdDev: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ping_rtt_stddev_seconds",
+				Help: "Standard deviation of round-trip time over the most recent ping run",
+			},
+			labels,
+		),
+		ReplyTTL: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ping_reply_ttl",
+				Help: "TTL reported on the most recent ICMP echo reply",
+			},
+			labels,
+		),
+		HTTPDuration: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "http_ping_duration_seconds",
+				Help: "Time taken by each phase of the most recent HTTP(S) probe",
+			},
+			append(append([]string{}, labels...), "phase"),
+		),
+		HTTPStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "http_ping_status_code",
+				Help: "HTTP status code of the most recent probe",
+			},
+			labels,
+		),
+		HTTPSuccess: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "http_ping_success",
+				Help: "Whether the most recent HTTP(S) probe got the expected status code",
+			},
+			labels,
+		),
+	}
+
+	reg.MustRegister(
+		m.RTT,
+		m.RTTHistogram,
+		m.RTTSummary,
+		m.PacketsSent,
+		m.PacketsRecv,
+		m.PacketsDuplicate,
+		m.PacketLossRatio,
+		m.RTTMin,
+		m.RTTAvg,
+		m.RTTMax,
+		m.RTTStdDev,
+		m.ReplyTTL,
+		m.HTTPDuration,
+		m.HTTPStatus,
+		m.HTTPSuccess,
+	)
+	return m
+}
+
+// observeHTTP updates the HTTP(S) collectors from an HTTPProber result.
+func (m *Metrics) observeHTTP(result *Result, labels ...string) {
+	for phase, d := range result.Phases {
+		phaseLabels := append(append([]string{}, labels...), phase)
+		m.HTTPDuration.WithLabelValues(phaseLabels...).Set(d.Seconds())
+	}
+	m.HTTPStatus.WithLabelValues(labels...).Set(float64(result.StatusCode))
+	success := 0.0
+	if result.Success {
+		success = 1.0
+	}
+	m.HTTPSuccess.WithLabelValues(labels...).Set(success)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// runScheduled repeatedly runs prober.Probe on target.Interval until ctx is
+// cancelled. HTTPProber results are recorded into metrics once Probe
+// returns; ICMPProber updates metrics live as packets arrive (see
+// ICMPProber.Probe), since an ICMP probe with the default infinite Count
+// may not return until shutdown.
+func runScheduled(ctx context.Context, target Target, prober Prober, metrics *Metrics, hostname string) error {
+	labels := []string{targetLabel(target), hostname}
+
+	probeOnce := func() {
+		result, err := prober.Probe(ctx)
+		if err != nil {
+			level.Error(logger).Log("msg", "probe failed", "target", target.Host, "err", err)
+			return
+		}
+
+		switch target.Mode {
+		case "http", "https":
+			metrics.observeHTTP(result, labels...)
+		}
+	}
+
+	probeOnce()
+
+	interval := time.Duration(target.Interval)
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			probeOnce()
+		}
+	}
+}